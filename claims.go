@@ -0,0 +1,65 @@
+/*
+ * Copyright 2018 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iam
+
+// Ban describes a single active ban on a user, as reported in
+// JWTClaims.Bans and checked by DefaultClient.HasBan.
+type Ban struct {
+	Ban string `json:"ban"`
+}
+
+// Permission is a single granted or required permission, matched by
+// PermissionMatcher. Resource is a `:`-segmented resource string (supporting
+// `*`/`**` wildcards, see defaultPermissionMatcher) and Action is a bitmask
+// of the CRUD actions it covers.
+type Permission struct {
+	Resource string `json:"Resource"`
+	Action   int    `json:"Action"`
+}
+
+// JWTClaims is the set of token claims this SDK inspects when validating
+// and authorizing an access token locally. It's a hand-rolled claims type
+// rather than an embedded jwt.StandardClaims because IAM's Audience is a
+// list (StandardClaims models it as a single string) and IAM mints its own
+// namespace/roles/permissions/justice-flags/ban claims that StandardClaims
+// doesn't have at all.
+type JWTClaims struct {
+	Subject      string       `json:"sub"`
+	Issuer       string       `json:"iss"`
+	IssuedAt     float64      `json:"iat"`
+	Namespace    string       `json:"namespace"`
+	Audience     []string     `json:"aud"`
+	Scope        string       `json:"scope"`
+	Permissions  []Permission `json:"permissions"`
+	Roles        []string     `json:"roles"`
+	JusticeFlags int          `json:"justice_flags"`
+	Bans         []Ban        `json:"bans"`
+	// Jti is the RFC 7519 `jti` claim. ValidateAndParseClaimsCtx rejects a
+	// token whose jti has been blacklisted via BlacklistTokens,
+	// SubscribeBlacklistFeed or a trusted issuer's revocation-list feed; see
+	// jtiBlacklisted in introspect.go.
+	Jti string `json:"jti"`
+}
+
+// Valid satisfies jwt.Claims so JWTClaims can be passed to
+// jwt.ParseWithClaims (see validateJWT/validateJWTMultiIssuer). It performs
+// no checks: token revocation is enforced separately by
+// ValidateAndParseClaimsCtx (userRevoked/tokenRevoked/jtiBlacklisted), and
+// the token's `exp` claim is neither parsed into JWTClaims nor checked.
+func (c *JWTClaims) Valid() error {
+	return nil
+}
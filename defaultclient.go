@@ -17,6 +17,7 @@
 package iam
 
 import (
+	"context"
 	"crypto/rsa"
 	"encoding/json"
 	"errors"
@@ -24,6 +25,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/AccelByte/bloom"
@@ -50,6 +52,7 @@ const (
 	defaultRoleCacheTime                 = 60 * time.Second
 	defaultJWKSRefreshInterval           = 60 * time.Second
 	defaultRevocationListRefreshInterval = 60 * time.Second
+	defaultBlacklistCacheTime            = 24 * time.Hour
 
 	baseURIKey             = "baseURI"
 	baseURICacheExpiration = 1 * time.Minute
@@ -64,12 +67,75 @@ type Config struct {
 	RolesCacheExpirationTime      time.Duration // default: 60s
 	JWKSRefreshInterval           time.Duration // default: 60s
 	RevocationListRefreshInterval time.Duration // default: 60s
+	// TrustedIssuers is an optional list of OIDC issuer URLs. When set,
+	// StartLocalValidation resolves jwks_uri, token_endpoint and
+	// revocation_endpoint for each issuer via OIDC discovery instead of the
+	// hard-coded jwksPath/grantPath/revocationListPath, enabling federated
+	// deployments where tokens are minted by more than one IAM instance. Of
+	// the three, only jwks_uri is generically OIDC-conformant: if one of the
+	// issuers is this client's own BaseURL, its discovered token_endpoint is
+	// used for ClientTokenGrant in place of the hard-coded grantPath, but
+	// revocation_endpoint is polled expecting AccelByte IAM's own bulk
+	// `{"revokedTokenIds": [...]}` list rather than performing RFC 7009
+	// single-token revocation, so revocation-list polling only works against
+	// an AccelByte-compatible issuer, not arbitrary third-party OIDC
+	// providers. The legacy revocation list at BaseURL is still fetched and
+	// refreshed alongside the issuers' own revocation endpoints.
+	TrustedIssuers []string
+	// IssuerJWKSRetryPolicy and IssuerRevocationRetryPolicy tune the
+	// decorrelated-jitter backoff used by each trusted issuer's background
+	// JWKS and revocation-list refresh loops (see addTrustedIssuer). Zero
+	// value falls back to a 1s base / 65s cap, same as TokenRetryPolicy.
+	IssuerJWKSRetryPolicy       RetryPolicy
+	IssuerRevocationRetryPolicy RetryPolicy
+	// JWKSRetryPolicy tunes the decorrelated-jitter backoff used by the
+	// legacy single-tenant JWKS refresh loop (refreshJWKS), started by
+	// StartLocalValidation when no TrustedIssuers are configured. Zero value
+	// falls back to a 1s base / 65s cap, same as TokenRetryPolicy.
+	JWKSRetryPolicy RetryPolicy
+	// TokenRetryPolicy tunes the decorrelated-jitter backoff used when the
+	// background token refresh loop fails. Zero value falls back to a 1s
+	// base / 65s cap.
+	TokenRetryPolicy RetryPolicy
+	// IntrospectCacheTime controls how long ValidateAccessTokenIntrospect
+	// caches a token's introspection result before re-checking with IAM.
+	// default: 60s
+	IntrospectCacheTime time.Duration
+	// BlacklistCacheTime controls how long a `jti` blacklisted via
+	// BlacklistTokens or SubscribeBlacklistFeed is kept before it's evicted.
+	// default: 24h
+	BlacklistCacheTime time.Duration
+	// InitialRefreshToken, if set, makes ClientTokenGrant start from the
+	// refresh_token grant instead of client_credentials. It is only
+	// consulted when SetTokenStorage wasn't called or its store had
+	// nothing persisted yet.
+	InitialRefreshToken string
+	// PermissionMatcher, if set, replaces the default hierarchical wildcard
+	// matcher used by ValidatePermission, e.g. to delegate to a Rego/OPA
+	// policy engine instead.
+	PermissionMatcher PermissionMatcher
+	// Tracer, if set, receives span events for JWKS fetch, revocation
+	// refresh, role-cache miss and permission evaluation, letting callers
+	// wire the SDK's internal work into OpenTelemetry or similar.
+	Tracer Tracer
 }
 
 // DefaultClient define oauth client config
 type DefaultClient struct {
-	keys                       map[string]*rsa.PublicKey
-	clientAccessToken          string
+	keys              map[string]*rsa.PublicKey
+	clientAccessToken string
+	// refreshToken is the rotating refresh token returned alongside the
+	// access token when the refresh_token grant is in use; see token.go and
+	// Config.InitialRefreshToken/SetTokenStorage.
+	refreshToken string
+	// tokenMu guards clientAccessToken and refreshToken: they're written by
+	// clientTokenGrant/refreshAccessToken (the background refresh goroutine),
+	// doTokenGrant and SetTokenStorage, and read by ClientToken, which can
+	// all happen concurrently.
+	tokenMu sync.RWMutex
+	// tokenStore, when set via SetTokenStorage, persists clientAccessToken
+	// and refreshToken across process restarts.
+	tokenStore                 TokenStore
 	config                     *Config
 	rolePermissionCache        *cache.Cache
 	revocationFilter           *bloom.Filter
@@ -79,8 +145,37 @@ type DefaultClient struct {
 	jwksRefreshError           error
 	revocationListRefreshError error
 	tokenRefreshError          error
-	remoteTokenValidation      func(accessToken string) (bool, error)
+	remoteTokenValidation      func(ctx context.Context, accessToken string) (bool, error)
 	baseURICache               *cache.Cache
+	// issuerEndpoints and issuerKeys back the OIDC discovery / multi-issuer
+	// mode enabled by passing Config.TrustedIssuers or calling
+	// StartLocalValidation with one or more issuer URLs.
+	issuerEndpoints map[string]*issuerEndpoints
+	issuerKeys      map[string]map[string]*rsa.PublicKey
+	// issuerRefresh tracks decorrelated-jitter backoff state for each trusted
+	// issuer's background JWKS and revocation-list refresh loops, keyed by
+	// issuer. See RefreshIssuerStats.
+	issuerRefresh map[string]*issuerRefreshState
+	// issuerMu guards issuerEndpoints, issuerKeys and issuerRefresh:
+	// addTrustedIssuer and the per-issuer refresh loops write to them from
+	// background goroutines while publicKeyForIssuer/ValidateAndParseClaimsCtx
+	// read them on every validation call.
+	issuerMu sync.RWMutex
+	// tokenRetry tracks decorrelated-jitter backoff state for the
+	// background token refresh loop.
+	tokenRetry *refreshRetryState
+	// jwksRetry tracks decorrelated-jitter backoff state for the legacy
+	// single-tenant JWKS refresh loop (refreshJWKS). The revocation-list
+	// counterpart has no equivalent yet; see getRevocationList.
+	jwksRetry *refreshRetryState
+	// introspectCache and tokenBlacklist back ValidateAccessTokenIntrospect
+	// (and, via jtiBlacklisted, local validation): introspectCache holds RFC
+	// 7662 results keyed by token hash, and tokenBlacklist records `jti`s
+	// revoked via BlacklistTokens/SubscribeBlacklistFeed, evicting them after
+	// Config.BlacklistCacheTime so cached introspection entries are
+	// invalidated immediately instead of waiting out their own TTL.
+	introspectCache *cache.Cache
+	tokenBlacklist  *cache.Cache
 	// for easily mocking the HTTP call
 	httpClient HTTPClient
 }
@@ -101,12 +196,30 @@ func NewDefaultClient(config *Config) Client {
 	if config.RevocationListRefreshInterval <= 0 {
 		config.RevocationListRefreshInterval = defaultRevocationListRefreshInterval
 	}
+	if config.IntrospectCacheTime <= 0 {
+		config.IntrospectCacheTime = defaultIntrospectCacheTime
+	}
+	if config.BlacklistCacheTime <= 0 {
+		config.BlacklistCacheTime = defaultBlacklistCacheTime
+	}
 
 	client := &DefaultClient{
 		config:              config,
 		rolePermissionCache: cache.New(config.RolesCacheExpirationTime, 2*config.RolesCacheExpirationTime),
+		issuerEndpoints:     make(map[string]*issuerEndpoints),
+		issuerKeys:          make(map[string]map[string]*rsa.PublicKey),
+		issuerRefresh:       make(map[string]*issuerRefreshState),
+		tokenRetry:          newRefreshRetryState(config.TokenRetryPolicy),
+		jwksRetry:           newRefreshRetryState(config.JWKSRetryPolicy),
+		introspectCache:     cache.New(config.IntrospectCacheTime, 2*config.IntrospectCacheTime),
+		tokenBlacklist:      cache.New(config.BlacklistCacheTime, 2*config.BlacklistCacheTime),
+	}
+	// validateAccessToken predates context support; wrap it so
+	// remoteTokenValidation still satisfies the ctx-aware signature used by
+	// ValidateAccessTokenCtx.
+	client.remoteTokenValidation = func(ctx context.Context, accessToken string) (bool, error) {
+		return client.validateAccessToken(accessToken)
 	}
-	client.remoteTokenValidation = client.validateAccessToken
 
 	client.baseURICache = cache.New(baseURICacheExpiration, baseURICacheExpiration)
 	client.httpClient = &http.Client{}
@@ -116,7 +229,14 @@ func NewDefaultClient(config *Config) Client {
 
 // ClientTokenGrant starts client token grant to get client bearer token for role caching
 func (client *DefaultClient) ClientTokenGrant() error {
-	refreshInterval, err := client.clientTokenGrant()
+	return client.ClientTokenGrantCtx(context.Background())
+}
+
+// ClientTokenGrantCtx is ClientTokenGrant with a caller-supplied context,
+// propagated to the underlying HTTP call via http.NewRequestWithContext so
+// callers can bound or cancel the initial grant.
+func (client *DefaultClient) ClientTokenGrantCtx(ctx context.Context) error {
+	refreshInterval, err := client.clientTokenGrant(ctx)
 	if err != nil {
 		return err
 	}
@@ -130,41 +250,138 @@ func (client *DefaultClient) ClientTokenGrant() error {
 
 // ClientToken returns client access token
 func (client *DefaultClient) ClientToken() string {
+	client.tokenMu.RLock()
+	defer client.tokenMu.RUnlock()
 	return client.clientAccessToken
 }
 
 // StartLocalValidation starts goroutines to refresh JWK and revocation list periodically
-// this enables local token validation
-func (client *DefaultClient) StartLocalValidation() error {
-	err := client.getJWKS()
-	if err != nil {
-		return fmt.Errorf("unable to get JWKS: %v", err)
+// this enables local token validation.
+// When trustedIssuers is non-empty, each issuer is resolved via OIDC
+// discovery (`/.well-known/openid-configuration`) and its JWKS is kept
+// separately per issuer so ValidateAndParseClaims can pick the correct key
+// based on the token's `iss` claim. trustedIssuers takes precedence over,
+// and is merged with, Config.TrustedIssuers. With no issuers given at all,
+// StartLocalValidation falls back to the legacy single-issuer behavior of
+// fetching JWKS/revocation list from Config.BaseURL.
+func (client *DefaultClient) StartLocalValidation(trustedIssuers ...string) error {
+	return client.StartLocalValidationCtx(context.Background(), trustedIssuers...)
+}
+
+// StartLocalValidationCtx is StartLocalValidation with a caller-supplied
+// context, propagated to the initial JWKS and discovery fetches and to the
+// Tracer events they emit. The initial getRevocationList call takes no
+// context, so ctx does not bound or cancel it. The background refresh goroutines it starts outlive ctx and refresh on
+// their own context, same as refreshAccessToken.
+func (client *DefaultClient) StartLocalValidationCtx(ctx context.Context, trustedIssuers ...string) error {
+	issuers := append(append([]string{}, client.config.TrustedIssuers...), trustedIssuers...)
+
+	if len(issuers) == 0 {
+		if err := client.getJWKS(ctx); err != nil {
+			return fmt.Errorf("unable to get JWKS: %v", err)
+		}
+
+		err := client.getRevocationList()
+		client.trace(ctx, TraceEventRevocationRefresh, err)
+		if err != nil {
+			return fmt.Errorf("unable to get revocation list: %v", err)
+		}
+
+		go client.refreshJWKS()
+		go client.refreshRevocationList()
+
+		client.localValidationActive = true
+		return nil
 	}
 
-	err = client.getRevocationList()
+	for _, issuerURL := range issuers {
+		if err := client.addTrustedIssuer(ctx, issuerURL); err != nil {
+			return fmt.Errorf("unable to start local validation for issuer %s: %v", issuerURL, err)
+		}
+	}
+
+	err := client.getRevocationList()
+	client.trace(ctx, TraceEventRevocationRefresh, err)
 	if err != nil {
 		return fmt.Errorf("unable to get revocation list: %v", err)
 	}
-
-	go client.refreshJWKS()
 	go client.refreshRevocationList()
 
 	client.localValidationActive = true
 	return nil
 }
 
+// getJWKS fetches the legacy single-tenant JWKS from
+// Config.BaseURL+jwksPath and replaces client.keys. Used by
+// StartLocalValidationCtx when no trusted issuers are configured; see
+// addTrustedIssuer/getJWKSFromURL for the OIDC-discovery equivalent.
+func (client *DefaultClient) getJWKS(ctx context.Context) error {
+	keys, err := client.getJWKSFromURL(ctx, client.config.BaseURL+jwksPath)
+	if err != nil {
+		return err
+	}
+	client.keys = keys
+	return nil
+}
+
+// refreshJWKS keeps the legacy single-tenant JWKS (client.keys) fresh for
+// the lifetime of the process, retrying with Config.JWKSRetryPolicy's
+// decorrelated-jitter backoff on failure and falling back to
+// Config.JWKSRefreshInterval on success, the same way refreshIssuerJWKS does
+// for a trusted issuer. Like refreshAccessToken, it isn't driven by a
+// caller context since it outlives any single request.
+func (client *DefaultClient) refreshJWKS() {
+	err := client.getJWKS(context.Background())
+	client.jwksRefreshError = err
+
+	var wait time.Duration
+	if err != nil {
+		wait = client.jwksRetry.onFailure(err)
+	} else {
+		client.jwksRetry.onSuccess()
+		wait = client.config.JWKSRefreshInterval
+	}
+
+	time.Sleep(wait)
+	client.refreshJWKS()
+}
+
 // ValidateAccessToken validates access token by calling IAM service
 func (client *DefaultClient) ValidateAccessToken(accessToken string) (bool, error) {
-	return client.remoteTokenValidation(accessToken)
+	return client.ValidateAccessTokenCtx(context.Background(), accessToken)
+}
+
+// ValidateAccessTokenCtx is ValidateAccessToken with a caller-supplied
+// context, propagated to the remote validation HTTP call.
+func (client *DefaultClient) ValidateAccessTokenCtx(ctx context.Context, accessToken string) (bool, error) {
+	return client.remoteTokenValidation(ctx, accessToken)
 }
 
 // ValidateAndParseClaims validates access token locally and returns the JWT claims contained in the token
 func (client *DefaultClient) ValidateAndParseClaims(accessToken string) (*JWTClaims, error) {
+	return client.ValidateAndParseClaimsCtx(context.Background(), accessToken)
+}
+
+// ValidateAndParseClaimsCtx is ValidateAndParseClaims with a caller-supplied
+// context. Local validation itself is signature verification against
+// already-cached keys and performs no I/O; ctx is accepted for API
+// consistency and is not otherwise used.
+func (client *DefaultClient) ValidateAndParseClaimsCtx(ctx context.Context, accessToken string) (*JWTClaims, error) {
 	if !client.localValidationActive {
 		return nil, errors.New("local validation is not active, activate by calling StartLocalValidation()")
 	}
 
-	claims, err := client.validateJWT(accessToken)
+	client.issuerMu.RLock()
+	hasTrustedIssuers := len(client.issuerKeys) > 0
+	client.issuerMu.RUnlock()
+
+	var claims *JWTClaims
+	var err error
+	if hasTrustedIssuers {
+		claims, err = client.validateJWTMultiIssuer(accessToken)
+	} else {
+		claims, err = client.validateJWT(accessToken)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("unable to verify JWT : %v", err)
 	}
@@ -174,16 +391,30 @@ func (client *DefaultClient) ValidateAndParseClaims(accessToken string) (*JWTCla
 	if client.tokenRevoked(accessToken) {
 		return nil, errors.New("token has been revoked")
 	}
+	if client.jtiBlacklisted(claims.Jti) {
+		return nil, errors.New("token has been revoked")
+	}
 
 	return claims, nil
 }
 
 // ValidatePermission validates if an access token has right for a specific permission
 // requiredPermission: permission to access resource, example:
-// 		{Resource: "NAMESPACE:{namespace}:USER:{userId}", Action: 2}
+//
+//	{Resource: "NAMESPACE:{namespace}:USER:{userId}", Action: 2}
+//
 // permissionResources: resource string to replace the `{}` placeholder in
-// 		`requiredPermission`, example: p["{namespace}"] = "accelbyte"
+//
+//	`requiredPermission`, example: p["{namespace}"] = "accelbyte"
 func (client *DefaultClient) ValidatePermission(claims *JWTClaims,
+	requiredPermission Permission, permissionResources map[string]string) (bool, error) {
+	return client.ValidatePermissionCtx(context.Background(), claims, requiredPermission, permissionResources)
+}
+
+// ValidatePermissionCtx is ValidatePermission with a caller-supplied
+// context, propagated to the role-permission lookup it may trigger and to
+// the TraceEventPermissionEvaluated event emitted via Config.Tracer.
+func (client *DefaultClient) ValidatePermissionCtx(ctx context.Context, claims *JWTClaims,
 	requiredPermission Permission, permissionResources map[string]string) (bool, error) {
 	if claims == nil {
 		return false, nil
@@ -191,11 +422,21 @@ func (client *DefaultClient) ValidatePermission(claims *JWTClaims,
 	for placeholder, value := range permissionResources {
 		requiredPermission.Resource = strings.Replace(requiredPermission.Resource, placeholder, value, 1)
 	}
+
+	allowed, err := client.evaluatePermission(ctx, claims, requiredPermission)
+	client.trace(ctx, TraceEventPermissionEvaluated, err)
+	return allowed, err
+}
+
+// evaluatePermission holds the actual permission-evaluation logic used by
+// ValidatePermissionCtx.
+func (client *DefaultClient) evaluatePermission(ctx context.Context, claims *JWTClaims,
+	requiredPermission Permission) (bool, error) {
 	if client.permissionAllowed(claims.Permissions, requiredPermission) {
 		return true, nil
 	}
 	for _, roleID := range claims.Roles {
-		grantedRolePermissions, err := client.getRolePermission(roleID)
+		grantedRolePermissions, err := client.getRolePermission(ctx, roleID)
 		if err != nil {
 			if err == errRoleNotFound {
 				continue
@@ -248,6 +489,50 @@ func (client *DefaultClient) HasBan(claims *JWTClaims, banType string) bool {
 	return false
 }
 
+// userRevoked reports whether subject's sessions were revoked at or after
+// issuedAt, i.e. the token was minted before an administrator force-revoked
+// that user (e.g. on password change), even though its signature and exp
+// are otherwise fine. client.revokedUsers is populated by
+// getRevocationList.
+func (client *DefaultClient) userRevoked(subject string, issuedAt int64) bool {
+	revokedAt, ok := client.revokedUsers[subject]
+	if !ok {
+		return false
+	}
+	return issuedAt <= revokedAt.Unix()
+}
+
+// RefreshStats reports the current decorrelated-jitter backoff state of the
+// background token and legacy single-tenant JWKS refresh loops, keyed by
+// "token" and "jwks". The legacy revocation-list refresh loop
+// (refreshRevocationList) has no backoff state of its own yet; callers who
+// want configurable backoff there today need to go through
+// Config.TrustedIssuers (even with a single, self-referential issuer) and
+// read RefreshIssuerStats instead.
+func (client *DefaultClient) RefreshStats() map[string]RetryStats {
+	return map[string]RetryStats{
+		"token": client.tokenRetry.stats(),
+		"jwks":  client.jwksRetry.stats(),
+	}
+}
+
+// RefreshIssuerStats reports the decorrelated-jitter backoff state of the
+// per-issuer JWKS and revocation-list refresh loops started for each of
+// Config.TrustedIssuers, keyed by issuer and then by "jwks"/"revocationList".
+func (client *DefaultClient) RefreshIssuerStats() map[string]map[string]RetryStats {
+	client.issuerMu.RLock()
+	defer client.issuerMu.RUnlock()
+
+	stats := make(map[string]map[string]RetryStats, len(client.issuerRefresh))
+	for issuer, state := range client.issuerRefresh {
+		stats[issuer] = map[string]RetryStats{
+			"jwks":           state.jwks.stats(),
+			"revocationList": state.revocation.stats(),
+		}
+	}
+	return stats
+}
+
 // HealthCheck lets caller know the health of the IAM client
 func (client *DefaultClient) HealthCheck() bool {
 	if client.jwksRefreshError != nil {
@@ -259,11 +544,29 @@ func (client *DefaultClient) HealthCheck() bool {
 	if client.tokenRefreshActive && client.tokenRefreshError != nil {
 		return false
 	}
+
+	client.issuerMu.RLock()
+	defer client.issuerMu.RUnlock()
+	for _, state := range client.issuerRefresh {
+		if state.jwks.stats().LastError != nil {
+			return false
+		}
+		if state.revocation.stats().LastError != nil {
+			return false
+		}
+	}
+
 	return true
 }
 
 // ValidateAudience validate audience of user access token
 func (client *DefaultClient) ValidateAudience(claims *JWTClaims) error {
+	return client.ValidateAudienceCtx(context.Background(), claims)
+}
+
+// ValidateAudienceCtx is ValidateAudience with a caller-supplied context,
+// propagated to the client-information HTTP call it may trigger.
+func (client *DefaultClient) ValidateAudienceCtx(ctx context.Context, claims *JWTClaims) error {
 	if claims == nil {
 		return errors.New("claims is nil")
 	}
@@ -276,7 +579,7 @@ func (client *DefaultClient) ValidateAudience(claims *JWTClaims) error {
 	if !found {
 		path := fmt.Sprintf(clientInformationPath, claims.Namespace, client.config.ClientID)
 		getClientInformationURL := client.config.BaseURL + path
-		err := client.getClientInformation(getClientInformationURL)
+		err := client.getClientInformation(ctx, getClientInformationURL)
 		if err != nil {
 			fmt.Printf("[IAM-Go-SDK] get client detail returns error: %v\n", err)
 			return err
@@ -320,13 +623,13 @@ func (client *DefaultClient) ValidateScope(claims *JWTClaims, reqScope string) e
 
 // getClientInformation get client base URI
 // need client access token for authorization
-func (client *DefaultClient) getClientInformation(getClientInformationURL string) (err error) {
+func (client *DefaultClient) getClientInformation(ctx context.Context, getClientInformationURL string) (err error) {
 
 	clientInformation := struct {
 		BaseURI string `json:"BaseUri"`
 	}{}
 
-	req, err := http.NewRequest(http.MethodGet, getClientInformationURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getClientInformationURL, nil)
 	if err != nil {
 		return fmt.Errorf("unable to create new http request: %v", err)
 	}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2018 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iam
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	introspectPath             = "/oauth/introspect"
+	defaultIntrospectCacheTime = 60 * time.Second
+)
+
+// introspectionResult is the subset of the RFC 7662 introspection response
+// the SDK cares about.
+type introspectionResult struct {
+	Active bool   `json:"active"`
+	Jti    string `json:"jti"`
+}
+
+// ValidateAccessTokenIntrospect validates accessToken against the IAM
+// service's RFC 7662 token introspection endpoint. Unlike
+// ValidateAccessToken, results are cached for Config.IntrospectCacheTime
+// (default 60s) keyed by a hash of the token, so repeated checks for the
+// same token don't round-trip to IAM every time. Cached entries are
+// invalidated immediately when BlacklistTokens reports their `jti` as
+// revoked, rather than waiting for the cache TTL to expire.
+func (client *DefaultClient) ValidateAccessTokenIntrospect(accessToken string) (bool, error) {
+	return client.ValidateAccessTokenIntrospectCtx(context.Background(), accessToken)
+}
+
+// ValidateAccessTokenIntrospectCtx is ValidateAccessTokenIntrospect with a
+// caller-supplied context, propagated to the introspection HTTP call on a
+// cache miss.
+func (client *DefaultClient) ValidateAccessTokenIntrospectCtx(ctx context.Context, accessToken string) (bool, error) {
+	cacheKey := hashToken(accessToken)
+
+	if cached, found := client.introspectCache.Get(cacheKey); found {
+		result := cached.(introspectionResult)
+		if client.jtiBlacklisted(result.Jti) {
+			client.introspectCache.Delete(cacheKey)
+			return false, nil
+		}
+		return result.Active, nil
+	}
+
+	result, err := client.introspectToken(ctx, accessToken)
+	if err != nil {
+		return false, err
+	}
+
+	if client.jtiBlacklisted(result.Jti) {
+		return false, nil
+	}
+
+	client.introspectCache.Set(cacheKey, *result, cache.DefaultExpiration)
+
+	return result.Active, nil
+}
+
+// introspectToken calls the IAM service's /oauth/introspect endpoint.
+func (client *DefaultClient) introspectToken(ctx context.Context, accessToken string) (*introspectionResult, error) {
+	form := url.Values{}
+	form.Set("token", accessToken)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.config.BaseURL+introspectPath,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new http request: %v", err)
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("Authorization", "Bearer "+client.ClientToken())
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to do http request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read body response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to introspect token : error code : %d, error message : %s",
+			resp.StatusCode, string(bodyBytes))
+	}
+
+	result := introspectionResult{}
+	err = json.Unmarshal(bodyBytes, &result)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal body: %v", err)
+	}
+
+	return &result, nil
+}
+
+// BlacklistTokens marks the given token IDs (`jti`) as revoked, immediately
+// invalidating any cached introspection result for them instead of waiting
+// for the 60s revocation-list refresh. Entries are evicted automatically
+// after Config.BlacklistCacheTime. It's safe to call concurrently with
+// itself and with ValidateAccessTokenIntrospect/ValidateAndParseClaims.
+func (client *DefaultClient) BlacklistTokens(jtis []string) {
+	for _, jti := range jtis {
+		client.tokenBlacklist.Set(jti, true, cache.DefaultExpiration)
+	}
+}
+
+// SubscribeBlacklistFeed starts a goroutine that forwards every batch of
+// revoked `jti`s received on feed to BlacklistTokens, letting callers wire
+// up their own polling loop or pub/sub subscription against an external
+// revocation feed without reaching into tokenBlacklist directly. The
+// goroutine exits when feed is closed.
+func (client *DefaultClient) SubscribeBlacklistFeed(feed <-chan []string) {
+	go func() {
+		for jtis := range feed {
+			client.BlacklistTokens(jtis)
+		}
+	}()
+}
+
+// jtiBlacklisted reports whether jti has been explicitly blacklisted via
+// BlacklistTokens (directly, via SubscribeBlacklistFeed, or via a trusted
+// issuer's revocation-list refresh).
+func (client *DefaultClient) jtiBlacklisted(jti string) bool {
+	if jti == "" {
+		return false
+	}
+	_, blacklisted := client.tokenBlacklist.Get(jti)
+	return blacklisted
+}
+
+// hashToken returns a cache key for accessToken without retaining the raw
+// token bytes in the cache.
+func hashToken(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return hex.EncodeToString(sum[:])
+}
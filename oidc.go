@@ -0,0 +1,386 @@
+/*
+ * Copyright 2018 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iam
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const oidcDiscoveryPath = "/.well-known/openid-configuration"
+
+// oidcDiscoveryDocument is the subset of RFC 8414 / OIDC discovery metadata
+// the SDK cares about.
+type oidcDiscoveryDocument struct {
+	Issuer             string `json:"issuer"`
+	JWKSURI            string `json:"jwks_uri"`
+	TokenEndpoint      string `json:"token_endpoint"`
+	RevocationEndpoint string `json:"revocation_endpoint"`
+}
+
+// issuerEndpoints holds the resolved endpoints for a single trusted issuer,
+// as discovered via its OIDC discovery document. tokenEndpoint is read by
+// doTokenGrant (see token.go), which uses it in place of the hard-coded
+// grantPath when this client's own Config.BaseURL is itself one of the
+// discovered issuers.
+type issuerEndpoints struct {
+	issuer             string
+	jwksURI            string
+	tokenEndpoint      string
+	revocationEndpoint string
+}
+
+// discoverIssuer fetches the issuer's `/.well-known/openid-configuration`
+// document and returns its resolved endpoints.
+func (client *DefaultClient) discoverIssuer(ctx context.Context, issuerURL string) (*issuerEndpoints, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuerURL+oidcDiscoveryPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new http request: %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to do http request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read body response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to get OIDC discovery document : error code : %d, error message : %s",
+			resp.StatusCode, string(bodyBytes))
+	}
+
+	document := oidcDiscoveryDocument{}
+	err = json.Unmarshal(bodyBytes, &document)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal body: %v", err)
+	}
+
+	if document.Issuer == "" {
+		document.Issuer = issuerURL
+	}
+
+	return &issuerEndpoints{
+		issuer:             document.Issuer,
+		jwksURI:            document.JWKSURI,
+		tokenEndpoint:      document.TokenEndpoint,
+		revocationEndpoint: document.RevocationEndpoint,
+	}, nil
+}
+
+// addTrustedIssuer runs OIDC discovery for issuerURL, fetches its JWKS and
+// stores the keys under the discovered issuer so that ValidateAndParseClaims
+// can pick the right key for tokens minted by that issuer. It then starts
+// background loops that keep that issuer's JWKS and revocation list fresh
+// for as long as the process runs, the same way the legacy single-tenant
+// path is kept fresh by refreshJWKS/refreshRevocationList.
+func (client *DefaultClient) addTrustedIssuer(ctx context.Context, issuerURL string) error {
+	endpoints, err := client.discoverIssuer(ctx, issuerURL)
+	if err != nil {
+		return fmt.Errorf("unable to discover issuer %s: %v", issuerURL, err)
+	}
+	if endpoints.jwksURI == "" {
+		return fmt.Errorf("issuer %s discovery document is missing jwks_uri", issuerURL)
+	}
+
+	client.issuerMu.Lock()
+	client.issuerEndpoints[endpoints.issuer] = endpoints
+	client.issuerMu.Unlock()
+
+	keys, err := client.getJWKSFromURL(ctx, endpoints.jwksURI)
+	if err != nil {
+		return fmt.Errorf("unable to get JWKS for issuer %s: %v", endpoints.issuer, err)
+	}
+
+	client.issuerMu.Lock()
+	client.issuerKeys[endpoints.issuer] = keys
+	client.issuerRefresh[endpoints.issuer] = &issuerRefreshState{
+		jwks:       newRefreshRetryState(client.config.IssuerJWKSRetryPolicy),
+		revocation: newRefreshRetryState(client.config.IssuerRevocationRetryPolicy),
+	}
+	client.issuerMu.Unlock()
+
+	go client.refreshIssuerJWKS(endpoints.issuer, endpoints.jwksURI)
+	if endpoints.revocationEndpoint != "" {
+		go client.refreshIssuerRevocationList(endpoints.issuer, endpoints.revocationEndpoint)
+	}
+
+	return nil
+}
+
+// issuerEndpoint returns the OIDC-discovered endpoints registered for
+// issuer, if any. Safe to call concurrently with addTrustedIssuer.
+func (client *DefaultClient) issuerEndpoint(issuer string) (*issuerEndpoints, bool) {
+	client.issuerMu.RLock()
+	defer client.issuerMu.RUnlock()
+
+	endpoints, ok := client.issuerEndpoints[issuer]
+	return endpoints, ok
+}
+
+// issuerRefreshState tracks decorrelated-jitter backoff state for one
+// trusted issuer's periodic JWKS and revocation-list refresh loops.
+type issuerRefreshState struct {
+	jwks       *refreshRetryState
+	revocation *refreshRetryState
+}
+
+// refreshIssuerJWKS periodically re-fetches and replaces the JWKS for a
+// single trusted issuer, so a signing-key rotation at that issuer doesn't
+// permanently break validation until process restart. Like
+// refreshAccessToken, it isn't driven by a caller context since it outlives
+// any single request.
+func (client *DefaultClient) refreshIssuerJWKS(issuer, jwksURI string) {
+	keys, err := client.getJWKSFromURL(context.Background(), jwksURI)
+
+	client.issuerMu.RLock()
+	state := client.issuerRefresh[issuer]
+	client.issuerMu.RUnlock()
+
+	var wait time.Duration
+	if err != nil {
+		wait = state.jwks.onFailure(err)
+	} else {
+		client.issuerMu.Lock()
+		client.issuerKeys[issuer] = keys
+		client.issuerMu.Unlock()
+		state.jwks.onSuccess()
+		wait = client.config.JWKSRefreshInterval
+	}
+
+	time.Sleep(wait)
+	client.refreshIssuerJWKS(issuer, jwksURI)
+}
+
+// issuerRevocationListResponse is the bulk revoked-token-ID feed this SDK
+// expects at an issuer's discovered revocation_endpoint. This matches
+// AccelByte IAM's own revocation-list convention, not RFC 7009: that RFC is
+// what revocation_endpoint actually means in OIDC discovery metadata, and it
+// defines a single-token POST ("revoke this token"), not a listing feed.
+// Polling a standards-only OIDC provider's revocation_endpoint this way will
+// 404/405. It mirrors BlacklistTokens' jti shape so a federated issuer's
+// revocations flow into the same blacklist consulted by
+// ValidateAccessTokenIntrospect.
+type issuerRevocationListResponse struct {
+	RevokedTokenIDs []string `json:"revokedTokenIds"`
+}
+
+// getIssuerRevocationList fetches revocationEndpoint and blacklists every
+// jti it reports as revoked. Only works against an AccelByte-compatible
+// issuer; see issuerRevocationListResponse.
+func (client *DefaultClient) getIssuerRevocationList(ctx context.Context, revocationEndpoint string) (err error) {
+	defer func() { client.trace(ctx, TraceEventRevocationRefresh, err) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, revocationEndpoint, nil)
+	if err != nil {
+		return fmt.Errorf("unable to create new http request: %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to do http request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read body response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to get revocation list : error code : %d, error message : %s",
+			resp.StatusCode, string(bodyBytes))
+	}
+
+	list := issuerRevocationListResponse{}
+	if err := json.Unmarshal(bodyBytes, &list); err != nil {
+		return fmt.Errorf("unable to unmarshal body: %v", err)
+	}
+
+	client.BlacklistTokens(list.RevokedTokenIDs)
+
+	return nil
+}
+
+// refreshIssuerRevocationList periodically re-fetches a trusted issuer's
+// revocation list. Like refreshIssuerJWKS, it runs for the lifetime of the
+// process on its own background context.
+func (client *DefaultClient) refreshIssuerRevocationList(issuer, revocationEndpoint string) {
+	err := client.getIssuerRevocationList(context.Background(), revocationEndpoint)
+
+	client.issuerMu.RLock()
+	state := client.issuerRefresh[issuer]
+	client.issuerMu.RUnlock()
+
+	var wait time.Duration
+	if err != nil {
+		wait = state.revocation.onFailure(err)
+	} else {
+		state.revocation.onSuccess()
+		wait = client.config.RevocationListRefreshInterval
+	}
+
+	time.Sleep(wait)
+	client.refreshIssuerRevocationList(issuer, revocationEndpoint)
+}
+
+// jwkSet is the RFC 7517 JSON Web Key Set shape returned by an issuer's
+// jwks_uri.
+type jwkSet struct {
+	Keys []struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// getJWKSFromURL fetches and parses the RSA public keys served at jwksURL,
+// keyed by `kid`.
+func (client *DefaultClient) getJWKSFromURL(ctx context.Context, jwksURL string) (keys map[string]*rsa.PublicKey, err error) {
+	defer func() { client.trace(ctx, TraceEventJWKSFetch, err) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new http request: %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to do http request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read body response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to get JWKS : error code : %d, error message : %s",
+			resp.StatusCode, string(bodyBytes))
+	}
+
+	set := jwkSet{}
+	err = json.Unmarshal(bodyBytes, &set)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal body: %v", err)
+	}
+
+	keys = make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, key := range set.Keys {
+		nBytes, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode modulus for kid %s: %v", key.Kid, err)
+		}
+		eBytes, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode exponent for kid %s: %v", key.Kid, err)
+		}
+
+		keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(nBytes),
+			E: int(new(big.Int).SetBytes(eBytes).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+// publicKeyForIssuer returns the public key registered for (iss, kid), or
+// false if the issuer or kid is unknown.
+func (client *DefaultClient) publicKeyForIssuer(iss, kid string) (*rsa.PublicKey, bool) {
+	client.issuerMu.RLock()
+	keys, ok := client.issuerKeys[iss]
+	client.issuerMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	// keys is replaced wholesale (never mutated in place) by
+	// refreshIssuerJWKS, so reading it after releasing issuerMu is safe.
+	key, ok := keys[kid]
+	return key, ok
+}
+
+// validateJWT verifies accessToken against the legacy single-tenant JWKS in
+// client.keys, populated by getJWKS/refreshJWKS. It's the counterpart used
+// instead of validateJWTMultiIssuer when no trusted issuers are configured.
+func (client *DefaultClient) validateJWT(accessToken string) (*JWTClaims, error) {
+	claims := &JWTClaims{}
+
+	_, err := jwt.ParseWithClaims(accessToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, errors.New("malformed token: no kid found")
+		}
+
+		key, ok := client.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid: %s", kid)
+		}
+
+		return key, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to validate JWT: %v", err)
+	}
+
+	return claims, nil
+}
+
+// validateJWTMultiIssuer verifies accessToken against the key registered for
+// its `iss`/`kid` pair, looking up client.issuerKeys populated via OIDC
+// discovery instead of the single-issuer client.keys used by validateJWT.
+func (client *DefaultClient) validateJWTMultiIssuer(accessToken string) (*JWTClaims, error) {
+	claims := &JWTClaims{}
+
+	_, err := jwt.ParseWithClaims(accessToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, errors.New("malformed token: no kid found")
+		}
+
+		key, ok := client.publicKeyForIssuer(claims.Issuer, kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown issuer/kid combination: %s/%s", claims.Issuer, kid)
+		}
+
+		return key, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to validate JWT: %v", err)
+	}
+
+	return claims, nil
+}
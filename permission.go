@@ -0,0 +1,174 @@
+/*
+ * Copyright 2018 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iam
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	resourceSegmentSeparator = ":"
+	resourceWildcardSegment  = "*"
+	resourceWildcardTail     = "**"
+
+	namespacePlaceholder = "{namespace}"
+	userIDPlaceholder    = "{userId}"
+)
+
+// errRoleNotFound is returned by getRolePermission when roleID doesn't
+// exist (IAM responds 404), letting evaluatePermission treat it as "this
+// role grants nothing" rather than a hard failure.
+var errRoleNotFound = errors.New("role not found")
+
+// PermissionMatcher decides whether a granted permission satisfies a
+// required one. Set Config.PermissionMatcher to plug in a custom matcher
+// (e.g. a Rego/OPA bridge) without forking the SDK; the zero value falls
+// back to defaultPermissionMatcher.
+type PermissionMatcher interface {
+	Match(granted, required Permission) bool
+}
+
+// defaultPermissionMatcher implements the hierarchical, `:`-segmented
+// wildcard matching used by Keycloak/Gatekeeper-style policy engines:
+// granted and required resources are compared segment-by-segment, where a
+// granted segment of `*` matches exactly one required segment and a
+// trailing `**` matches the rest of the required resource, however many
+// segments that is. Actions are still checked as an AND-ed bitmask.
+type defaultPermissionMatcher struct{}
+
+func (defaultPermissionMatcher) Match(granted, required Permission) bool {
+	if granted.Action&required.Action != required.Action {
+		return false
+	}
+	return matchResource(strings.Split(granted.Resource, resourceSegmentSeparator),
+		strings.Split(required.Resource, resourceSegmentSeparator))
+}
+
+// matchResource compares granted and required resource segments one at a
+// time. `*` matches exactly one required segment; `**` is only meaningful
+// as the final granted segment and matches every remaining required
+// segment, including zero of them.
+func matchResource(granted, required []string) bool {
+	for i, grantedSegment := range granted {
+		if grantedSegment == resourceWildcardTail {
+			// ** is only meaningful as the final granted segment; anywhere
+			// else it's a malformed pattern, so fail closed instead of
+			// silently dropping the segments that follow it.
+			return i == len(granted)-1
+		}
+		if i >= len(required) {
+			return false
+		}
+		if grantedSegment != resourceWildcardSegment && grantedSegment != required[i] {
+			return false
+		}
+	}
+	return len(granted) == len(required)
+}
+
+// permissionAllowed reports whether any of grantedPermissions satisfies
+// requiredPermission, using client.config.PermissionMatcher if one was set
+// or defaultPermissionMatcher otherwise.
+func (client *DefaultClient) permissionAllowed(grantedPermissions []Permission, requiredPermission Permission) bool {
+	matcher := client.config.PermissionMatcher
+	if matcher == nil {
+		matcher = defaultPermissionMatcher{}
+	}
+
+	for _, granted := range grantedPermissions {
+		if matcher.Match(granted, requiredPermission) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// roleResponse is the subset of IAM's role detail response
+// getRolePermission cares about.
+type roleResponse struct {
+	Permissions []Permission `json:"Permissions"`
+}
+
+// getRolePermission returns the permissions granted by roleID, consulting
+// client.rolePermissionCache (TTL: Config.RolesCacheExpirationTime) before
+// falling back to IAM's roles endpoint. A cache miss fires
+// TraceEventRoleCacheMiss via Config.Tracer.
+func (client *DefaultClient) getRolePermission(ctx context.Context, roleID string) (permissions []Permission, err error) {
+	if cached, found := client.rolePermissionCache.Get(roleID); found {
+		return cached.([]Permission), nil
+	}
+
+	defer func() { client.trace(ctx, TraceEventRoleCacheMiss, err) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, client.config.BaseURL+getRolePath+"/"+roleID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new http request: %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer "+client.ClientToken())
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to do http request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read body response: %v", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errRoleNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to get role permission : error code : %d, error message : %s",
+			resp.StatusCode, string(bodyBytes))
+	}
+
+	role := roleResponse{}
+	if err := json.Unmarshal(bodyBytes, &role); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal body: %v", err)
+	}
+
+	client.rolePermissionCache.Set(roleID, role.Permissions, cache.DefaultExpiration)
+
+	return role.Permissions, nil
+}
+
+// applyUserPermissionResourceValues substitutes the `{namespace}`/`{userId}`
+// placeholders a role's permission resources commonly carry with the values
+// from claims, the same substitution ValidatePermissionCtx applies to
+// requiredPermission via its permissionResources argument.
+func (client *DefaultClient) applyUserPermissionResourceValues(permissions []Permission, claims *JWTClaims) []Permission {
+	resolved := make([]Permission, len(permissions))
+	replacer := strings.NewReplacer(namespacePlaceholder, claims.Namespace, userIDPlaceholder, claims.Subject)
+	for i, permission := range permissions {
+		permission.Resource = replacer.Replace(permission.Resource)
+		resolved[i] = permission
+	}
+	return resolved
+}
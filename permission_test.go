@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iam
+
+import "testing"
+
+func TestMatchResource(t *testing.T) {
+	tests := []struct {
+		name     string
+		granted  []string
+		required []string
+		want     bool
+	}{
+		{
+			name:     "exact match",
+			granted:  []string{"NAMESPACE", "accelbyte", "USER", "user1"},
+			required: []string{"NAMESPACE", "accelbyte", "USER", "user1"},
+			want:     true,
+		},
+		{
+			name:     "exact mismatch",
+			granted:  []string{"NAMESPACE", "accelbyte", "USER", "user1"},
+			required: []string{"NAMESPACE", "accelbyte", "USER", "user2"},
+			want:     false,
+		},
+		{
+			name:     "single-segment wildcard matches exactly one segment",
+			granted:  []string{"NAMESPACE", "*", "USER", "*"},
+			required: []string{"NAMESPACE", "accelbyte", "USER", "user1"},
+			want:     true,
+		},
+		{
+			name:     "single-segment wildcard does not match multiple segments",
+			granted:  []string{"NAMESPACE", "*"},
+			required: []string{"NAMESPACE", "accelbyte", "USER"},
+			want:     false,
+		},
+		{
+			name:     "terminal ** matches remaining segments",
+			granted:  []string{"NAMESPACE", "accelbyte", "**"},
+			required: []string{"NAMESPACE", "accelbyte", "USER", "user1", "PROFILE"},
+			want:     true,
+		},
+		{
+			name:     "terminal ** matches zero remaining segments",
+			granted:  []string{"NAMESPACE", "accelbyte", "**"},
+			required: []string{"NAMESPACE", "accelbyte"},
+			want:     true,
+		},
+		{
+			name:     "non-terminal ** fails closed instead of matching everything",
+			granted:  []string{"NAMESPACE", "**", "ADMIN"},
+			required: []string{"NAMESPACE", "accelbyte", "USER"},
+			want:     false,
+		},
+		{
+			name:     "required shorter than granted",
+			granted:  []string{"NAMESPACE", "accelbyte", "USER", "user1"},
+			required: []string{"NAMESPACE", "accelbyte"},
+			want:     false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := matchResource(test.granted, test.required)
+			if got != test.want {
+				t.Errorf("matchResource(%v, %v) = %v, want %v", test.granted, test.required, got, test.want)
+			}
+		})
+	}
+}
+
+func BenchmarkMatchResourceExact(b *testing.B) {
+	granted := []string{"NAMESPACE", "accelbyte", "USER", "user1"}
+	required := []string{"NAMESPACE", "accelbyte", "USER", "user1"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		matchResource(granted, required)
+	}
+}
+
+func BenchmarkMatchResourceWildcardSegment(b *testing.B) {
+	granted := []string{"NAMESPACE", "*", "USER", "*"}
+	required := []string{"NAMESPACE", "accelbyte", "USER", "user1"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		matchResource(granted, required)
+	}
+}
+
+func BenchmarkMatchResourceWildcardTail(b *testing.B) {
+	granted := []string{"NAMESPACE", "accelbyte", "**"}
+	required := []string{"NAMESPACE", "accelbyte", "USER", "user1", "PROFILE"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		matchResource(granted, required)
+	}
+}
+
+func BenchmarkPermissionAllowed(b *testing.B) {
+	client := &DefaultClient{config: &Config{}}
+	granted := []Permission{
+		{Resource: "NAMESPACE:*:USER:*", Action: 15},
+		{Resource: "NAMESPACE:accelbyte:**", Action: 2},
+	}
+	required := Permission{Resource: "NAMESPACE:accelbyte:USER:user1", Action: 2}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client.permissionAllowed(granted, required)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2018 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iam
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRetryBaseDelay = 1 * time.Second
+	defaultRetryMaxDelay  = maxBackOffTime
+)
+
+// RetryPolicy configures the decorrelated-jitter backoff used when
+// refreshing JWKS, the revocation list and client tokens. Base is the delay
+// the backoff resets to after a success and the floor for every retry, Max
+// caps how long a single retry can wait.
+type RetryPolicy struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.Base <= 0 {
+		p.Base = defaultRetryBaseDelay
+	}
+	if p.Max <= 0 {
+		p.Max = defaultRetryMaxDelay
+	}
+	return p
+}
+
+// next returns the delay to wait before the next attempt, given the
+// previous delay, using AWS's "decorrelated jitter" formula:
+// sleep = min(cap, random_between(base, prev*3)).
+func (p RetryPolicy) next(prev time.Duration) time.Duration {
+	if prev < p.Base {
+		prev = p.Base
+	}
+	upper := prev * 3
+	if upper > p.Max {
+		upper = p.Max
+	}
+	if upper <= p.Base {
+		return p.Base
+	}
+	jittered := p.Base + time.Duration(rand.Int63n(int64(upper-p.Base)))
+	if jittered > p.Max {
+		jittered = p.Max
+	}
+	return jittered
+}
+
+// RetryStats reports the current backoff state for one of the SDK's
+// background refresh loops.
+type RetryStats struct {
+	Attempts     int
+	CurrentDelay time.Duration
+	LastError    error
+}
+
+// refreshRetryState tracks decorrelated-jitter backoff for a single
+// background refresh loop (JWKS, revocation list or token refresh).
+type refreshRetryState struct {
+	mutex   sync.Mutex
+	policy  RetryPolicy
+	attempt int
+	delay   time.Duration
+	lastErr error
+}
+
+func newRefreshRetryState(policy RetryPolicy) *refreshRetryState {
+	policy = policy.withDefaults()
+	return &refreshRetryState{
+		policy: policy,
+		delay:  policy.Base,
+	}
+}
+
+// onFailure records a failed attempt and returns how long to sleep before
+// retrying.
+func (s *refreshRetryState) onFailure(err error) time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.attempt++
+	s.lastErr = err
+	s.delay = s.policy.next(s.delay)
+	return s.delay
+}
+
+// onSuccess resets the backoff state to its base delay.
+func (s *refreshRetryState) onSuccess() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.attempt = 0
+	s.lastErr = nil
+	s.delay = s.policy.Base
+}
+
+func (s *refreshRetryState) stats() RetryStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return RetryStats{
+		Attempts:     s.attempt,
+		CurrentDelay: s.delay,
+		LastError:    s.lastErr,
+	}
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright 2018 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iam
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeRefreshToken      = "refresh_token"
+)
+
+// TokenStore persists the access/refresh token pair obtained via
+// ClientTokenGrant so long-lived daemons don't need to re-authenticate
+// from scratch on every restart. Implementations might back this with a
+// file, Redis, or any other durable store.
+type TokenStore interface {
+	// SaveToken persists accessToken and refreshToken.
+	SaveToken(accessToken, refreshToken string) error
+	// LoadToken returns the last persisted access and refresh token. Both
+	// return values are empty if nothing has been persisted yet.
+	LoadToken() (accessToken, refreshToken string, err error)
+}
+
+// tokenGrantResponse is the subset of the IAM token grant response the SDK
+// cares about.
+type tokenGrantResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// SetTokenStorage registers store so the client's access/refresh token pair
+// survives process restarts: the pair last persisted to store is loaded
+// immediately if it includes a refresh token (a persisted access token
+// without one is ignored), and every subsequent grant/refresh writes the
+// new pair back.
+func (client *DefaultClient) SetTokenStorage(store TokenStore) error {
+	accessToken, refreshToken, err := store.LoadToken()
+	if err != nil {
+		return fmt.Errorf("unable to load token from storage: %v", err)
+	}
+
+	client.tokenStore = store
+
+	client.tokenMu.Lock()
+	if refreshToken != "" {
+		client.clientAccessToken = accessToken
+		client.refreshToken = refreshToken
+	}
+	client.tokenMu.Unlock()
+
+	return nil
+}
+
+// clientTokenGrant performs the initial token grant. It uses the
+// refresh_token grant when a refresh token is already known, either from a
+// TokenStore loaded via SetTokenStorage or from Config.InitialRefreshToken,
+// and falls back to client_credentials otherwise.
+func (client *DefaultClient) clientTokenGrant(ctx context.Context) (time.Duration, error) {
+	client.tokenMu.Lock()
+	if client.refreshToken == "" {
+		client.refreshToken = client.config.InitialRefreshToken
+	}
+	client.tokenMu.Unlock()
+
+	refreshInterval, err := client.doTokenGrant(ctx, client.preferredGrantType())
+	if err != nil && client.hasRefreshToken() && isInvalidGrantError(err) {
+		client.clearRefreshToken()
+		return client.doTokenGrant(ctx, grantTypeClientCredentials)
+	}
+
+	return refreshInterval, err
+}
+
+// refreshAccessToken is run by the background goroutine started from
+// ClientTokenGrant. It prefers the refresh_token grant when a refresh token
+// is held, falling back to client_credentials if IAM rejects it with
+// invalid_grant, then reschedules itself using a decorrelated-jitter
+// backoff on failure. It isn't driven by a caller context since it outlives
+// any single request; each attempt uses a fresh context.Background().
+func (client *DefaultClient) refreshAccessToken() {
+	ctx := context.Background()
+
+	refreshInterval, err := client.doTokenGrant(ctx, client.preferredGrantType())
+	if err != nil && client.hasRefreshToken() && isInvalidGrantError(err) {
+		client.clearRefreshToken()
+		refreshInterval, err = client.doTokenGrant(ctx, grantTypeClientCredentials)
+	}
+
+	client.tokenRefreshError = err
+	if err != nil {
+		refreshInterval = client.tokenRetry.onFailure(err)
+	} else {
+		client.tokenRetry.onSuccess()
+	}
+
+	time.Sleep(refreshInterval)
+	client.refreshAccessToken()
+}
+
+// preferredGrantType reports which grant clientTokenGrant/refreshAccessToken
+// should attempt first.
+func (client *DefaultClient) preferredGrantType() string {
+	if client.hasRefreshToken() {
+		return grantTypeRefreshToken
+	}
+	return grantTypeClientCredentials
+}
+
+// hasRefreshToken reports whether a refresh token is currently held.
+func (client *DefaultClient) hasRefreshToken() bool {
+	client.tokenMu.RLock()
+	defer client.tokenMu.RUnlock()
+	return client.refreshToken != ""
+}
+
+// clearRefreshToken drops the held refresh token, e.g. after IAM rejects it
+// with invalid_grant, so the next grant attempt falls back to
+// client_credentials.
+func (client *DefaultClient) clearRefreshToken() {
+	client.tokenMu.Lock()
+	client.refreshToken = ""
+	client.tokenMu.Unlock()
+}
+
+// doTokenGrant executes grantType against IAM's token endpoint, stores the
+// returned access/refresh token pair (persisting it to tokenStore if one
+// was registered via SetTokenStorage), and returns the interval the caller
+// should wait before the next refresh. The hard-coded grantPath is used
+// unless Config.BaseURL was itself passed as a trusted issuer (directly or
+// via Config.TrustedIssuers), in which case the token_endpoint resolved by
+// that issuer's OIDC discovery document takes precedence.
+func (client *DefaultClient) doTokenGrant(ctx context.Context, grantType string) (time.Duration, error) {
+	form := url.Values{}
+	form.Set("grant_type", grantType)
+	if grantType == grantTypeRefreshToken {
+		client.tokenMu.RLock()
+		refreshToken := client.refreshToken
+		client.tokenMu.RUnlock()
+		form.Set("refresh_token", refreshToken)
+	}
+
+	tokenURL := client.config.BaseURL + grantPath
+	if endpoints, ok := client.issuerEndpoint(client.config.BaseURL); ok && endpoints.tokenEndpoint != "" {
+		tokenURL = endpoints.tokenEndpoint
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, fmt.Errorf("unable to create new http request: %v", err)
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(client.config.ClientID, client.config.ClientSecret)
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("unable to do http request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read body response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unable to do token grant : error code : %d, error message : %s",
+			resp.StatusCode, string(bodyBytes))
+	}
+
+	grantResult := tokenGrantResponse{}
+	err = json.Unmarshal(bodyBytes, &grantResult)
+	if err != nil {
+		return 0, fmt.Errorf("unable to unmarshal body: %v", err)
+	}
+
+	client.tokenMu.Lock()
+	client.clientAccessToken = grantResult.AccessToken
+	// Only overwrite refreshToken when the response actually returned one:
+	// a provider that doesn't rotate on every call, or the client_credentials
+	// fallback in clientTokenGrant/refreshAccessToken, can legitimately omit
+	// it, and blanking a previously-good refresh token would permanently
+	// downgrade the client to client_credentials-only.
+	if grantResult.RefreshToken != "" {
+		client.refreshToken = grantResult.RefreshToken
+	}
+	accessToken, refreshToken := client.clientAccessToken, client.refreshToken
+	client.tokenMu.Unlock()
+
+	if client.tokenStore != nil {
+		if err := client.tokenStore.SaveToken(accessToken, refreshToken); err != nil {
+			fmt.Printf("[IAM-Go-SDK] unable to persist token to storage: %v\n", err)
+		}
+	}
+
+	refreshInterval := time.Duration(float64(grantResult.ExpiresIn)*defaultTokenRefreshRate) * time.Second
+
+	return refreshInterval, nil
+}
+
+// isInvalidGrantError reports whether err is IAM rejecting a grant with
+// error=invalid_grant, e.g. because a rotating refresh token was already
+// consumed or has expired.
+func isInvalidGrantError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "invalid_grant")
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2018 AccelByte Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iam
+
+import "context"
+
+// TraceEvent identifies a point in the SDK's request path or background
+// work that Config.Tracer can observe.
+type TraceEvent string
+
+const (
+	// TraceEventJWKSFetch fires for every JWKS fetch: the per-issuer
+	// refresh loops started by addTrustedIssuer (Config.TrustedIssuers /
+	// OIDC discovery, both the initial fetch and every steady-state
+	// refreshIssuerJWKS iteration), and the legacy single-tenant path's
+	// initial getJWKS call and every steady-state refreshJWKS iteration
+	// alike, since both ultimately fetch through getJWKSFromURL.
+	//
+	// TraceEventRevocationRefresh fires for the per-issuer revocation-list
+	// loop (getIssuerRevocationList/refreshIssuerRevocationList) and for the
+	// legacy single-tenant path's initial getRevocationList call made by
+	// StartLocalValidationCtx. The legacy steady-state refreshRevocationList
+	// loop does not emit it, so its steady-state fetches aren't covered;
+	// callers who need full Tracer coverage of the
+	// revocation-list steady state today should add their own BaseURL to
+	// Config.TrustedIssuers, which is covered end-to-end by
+	// refreshIssuerRevocationList.
+	TraceEventJWKSFetch           TraceEvent = "iam.jwks_fetch"
+	TraceEventRevocationRefresh   TraceEvent = "iam.revocation_refresh"
+	TraceEventRoleCacheMiss       TraceEvent = "iam.role_cache_miss"
+	TraceEventPermissionEvaluated TraceEvent = "iam.permission_evaluated"
+)
+
+// Tracer receives a callback for each TraceEvent the SDK emits, letting
+// callers wire span creation into OpenTelemetry or any other tracing
+// backend. err is non-nil when the event represents a failed operation
+// (e.g. a JWKS fetch that returned a non-200, or a permission evaluation
+// whose role lookup failed); it is nil when the operation succeeded,
+// whatever the allow/deny outcome of a permission evaluation.
+type Tracer interface {
+	OnEvent(ctx context.Context, event TraceEvent, err error)
+}
+
+// trace invokes client.config.Tracer, if one is configured, for event.
+func (client *DefaultClient) trace(ctx context.Context, event TraceEvent, err error) {
+	if client.config.Tracer == nil {
+		return
+	}
+	client.config.Tracer.OnEvent(ctx, event, err)
+}